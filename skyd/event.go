@@ -0,0 +1,15 @@
+package skyd
+
+import "time"
+
+// An Event is a single timestamped fact recorded against an object.
+type Event struct {
+	Timestamp time.Time              `json:"timestamp"`
+	Data      map[string]interface{} `json:"data"`
+
+	// VectorClock records, per origin node, the highest event counter that
+	// node had produced when this event was created. Replicas compare it
+	// instead of Timestamp to decide whether an incoming event supersedes,
+	// is superseded by, or is concurrent with one they already have.
+	VectorClock VersionVector `json:"vectorClock,omitempty"`
+}
@@ -0,0 +1,68 @@
+package skyd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// manifestFileName is the name of the file, stored in the table root, that
+// records the table's tablet count and hash scheme version.
+const manifestFileName = "manifest.json"
+
+// A Manifest records the on-disk layout of a table so that Open can restore
+// the same tablet topology a table was created or resharded with.
+type Manifest struct {
+	TabletCount int    `json:"tabletCount"`
+	HashScheme  int    `json:"hashScheme"`
+	Partitioner string `json:"partitioner"`
+
+	// VnodesPerTablet is only meaningful when Partitioner is
+	// "consistent-hash"; it records the VnodesPerTablet the table's
+	// ConsistentHashPartitioner was constructed with, so Open restores a
+	// partitioner that assigns objects identically to the one the table
+	// was created or resharded with, rather than silently falling back
+	// to defaultVnodesPerTablet.
+	VnodesPerTablet int `json:"vnodesPerTablet,omitempty"`
+}
+
+// manifestFor builds the Manifest to persist for the table's current
+// partitioner at the given tablet count.
+func (t *Table) manifestFor(tabletCount int) *Manifest {
+	m := &Manifest{TabletCount: tabletCount, HashScheme: 1, Partitioner: t.partitioner.Name()}
+	if chp, ok := t.partitioner.(*ConsistentHashPartitioner); ok {
+		m.VnodesPerTablet = chp.VnodesPerTablet
+	}
+	return m
+}
+
+// Returns the path of the table's manifest file.
+func (t *Table) manifestPath() string {
+	return filepath.Join(t.Path, manifestFileName)
+}
+
+// Loads the manifest from disk.
+func (t *Table) loadManifest() (*Manifest, error) {
+	f, err := os.Open(t.manifestPath())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	m := &Manifest{}
+	if err := json.NewDecoder(f).Decode(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Writes the manifest to disk.
+func (t *Table) saveManifest(m *Manifest) error {
+	f, err := os.Create(t.manifestPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(m)
+}
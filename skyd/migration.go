@@ -0,0 +1,177 @@
+package skyd
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// formatVersionFileName is the name of the file, stored in the table root,
+// that records the on-disk layout version a table was written with.
+const formatVersionFileName = "format-version"
+
+// currentFormatVersion is the newest format version this build knows how
+// to read and write. Open refuses to open a table with a newer version
+// than this.
+const currentFormatVersion = 1
+
+// A migration upgrades a table in place from one format version to the
+// next.
+type migration struct {
+	from, to int
+	fn       func(*Table) error
+}
+
+// migrations is the registered chain of upgrades, keyed by the version
+// they upgrade from. Open walks this chain one step at a time until the
+// table reaches currentFormatVersion.
+var migrations = map[int]migration{}
+
+// RegisterMigration adds an upgrade step that rewrites a table in place
+// from format version `from` to `to`. Registering a step for a version
+// that already has one replaces it. Open only runs steps that start below
+// currentFormatVersion; a step registered from currentFormatVersion or a
+// later version is never run.
+func RegisterMigration(from, to int, fn func(*Table) error) {
+	migrations[from] = migration{from: from, to: to, fn: fn}
+}
+
+func init() {
+	// v1 is the baseline format. The step from v0 (a table with no
+	// format-version file) changes nothing on disk; migrate then stamps
+	// the table as v1. It does not create a manifest.json, so a v0 table
+	// without one still fails to open.
+	RegisterMigration(0, 1, func(t *Table) error { return nil })
+}
+
+// Returns the path of the table's format-version file.
+func (t *Table) formatVersionPath() string {
+	return filepath.Join(t.Path, formatVersionFileName)
+}
+
+// Reads the format version a table was written with. Tables written before
+// format-version files existed are treated as version 0.
+func (t *Table) readFormatVersion() (int, error) {
+	data, err := ioutil.ReadFile(t.formatVersionPath())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	version, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil {
+		return 0, fmt.Errorf("Invalid format-version file: %v", err)
+	}
+	return version, nil
+}
+
+// Writes the table's format-version file.
+func (t *Table) writeFormatVersion(version int) error {
+	return ioutil.WriteFile(t.formatVersionPath(), []byte(strconv.Itoa(version)), 0600)
+}
+
+// migrate walks the registered migration chain, upgrading the table one
+// step at a time until it reaches currentFormatVersion. Each step backs up
+// the table root to a `.bak` directory first so a failed migration can
+// fall back to the pre-migration state.
+func (t *Table) migrate() error {
+	version, err := t.readFormatVersion()
+	if err != nil {
+		return err
+	}
+
+	if version > currentFormatVersion {
+		return fmt.Errorf("Table format version %v is newer than supported version %v", version, currentFormatVersion)
+	}
+
+	for version < currentFormatVersion {
+		step, ok := migrations[version]
+		if !ok {
+			return fmt.Errorf("No migration registered from format version %v", version)
+		}
+
+		backupPath := t.Path + ".bak"
+		if err := os.RemoveAll(backupPath); err != nil {
+			return err
+		}
+		if err := copyDir(t.Path, backupPath); err != nil {
+			return err
+		}
+
+		if err := step.fn(t); err != nil {
+			return fmt.Errorf("Migration from v%v to v%v failed, original table preserved at %v: %v", step.from, step.to, backupPath, err)
+		}
+
+		if err := t.writeFormatVersion(step.to); err != nil {
+			return err
+		}
+
+		version = step.to
+	}
+
+	return nil
+}
+
+// copyDir recursively copies a directory tree, used by migrate to leave a
+// `.bak` fallback before rewriting a table in place.
+func copyDir(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dst, info.Mode()); err != nil {
+		return err
+	}
+
+	entries, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
+
+		if entry.IsDir() {
+			if err := copyDir(srcPath, dstPath); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := copyFile(srcPath, dstPath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// copyFile copies a single file, preserving its mode.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, in)
+	return err
+}
@@ -0,0 +1,58 @@
+package skyd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateWritesCurrentFormatVersion(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	version, err := table.readFormatVersion()
+	if err != nil {
+		t.Fatalf("readFormatVersion: %v", err)
+	}
+	if version != currentFormatVersion {
+		t.Fatalf("expected format version %v, got %v", currentFormatVersion, version)
+	}
+}
+
+func TestOpenRefusesNewerFormatVersion(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.writeFormatVersion(currentFormatVersion + 1); err != nil {
+		t.Fatalf("writeFormatVersion: %v", err)
+	}
+
+	if err := table.Open(); err == nil {
+		t.Fatalf("expected Open to refuse a table newer than currentFormatVersion")
+	}
+}
+
+func TestMigrateUpgradesBaselineVersionZero(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.writeFormatVersion(0); err != nil {
+		t.Fatalf("writeFormatVersion: %v", err)
+	}
+
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	version, err := table.readFormatVersion()
+	if err != nil {
+		t.Fatalf("readFormatVersion: %v", err)
+	}
+	if version != currentFormatVersion {
+		t.Fatalf("expected migration to leave the table at version %v, got %v", currentFormatVersion, version)
+	}
+}
@@ -0,0 +1,44 @@
+package skyd
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// EncodeObjectId converts an arbitrary object identifier into a canonical
+// byte slice so it can be hashed consistently and used as an on-disk key.
+// Byte slices and strings are already canonical and are used as-is;
+// int, int64 and uint64 are encoded as 8 big-endian bytes; everything
+// else falls back to its default string representation.
+func EncodeObjectId(objectId interface{}) ([]byte, error) {
+	switch v := objectId.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	case int:
+		return encodeUint64(uint64(v)), nil
+	case int64:
+		return encodeUint64(uint64(v)), nil
+	case uint64:
+		return encodeUint64(v), nil
+	default:
+		return []byte(fmt.Sprintf("%v", v)), nil
+	}
+}
+
+func encodeUint64(v uint64) []byte {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, v)
+	return buf
+}
+
+// CondenseUint64Even folds a 64-bit hash down to 32 bits by keeping only
+// its even bits (bit 2i of v becomes bit i of the result).
+func CondenseUint64Even(v uint64) uint32 {
+	var result uint32
+	for i := uint(0); i < 32; i++ {
+		result |= uint32((v>>(2*i))&1) << i
+	}
+	return result
+}
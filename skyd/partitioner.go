@@ -0,0 +1,146 @@
+package skyd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"sync"
+)
+
+// A Partitioner decides which of n tablets owns a given object. Extracting
+// this from Table lets a table use consistent hashing or a user-supplied
+// scheme instead of the default even hash, without touching the rest of
+// the table's code.
+type Partitioner interface {
+	// Name identifies the partitioner so it can be persisted in the table
+	// manifest and restored on Open.
+	Name() string
+
+	// Assign returns the index, in [0, n), of the tablet that owns
+	// objectId.
+	Assign(objectId interface{}, n int) (uint32, error)
+}
+
+// partitionerByName restores the partitioner a table was created or
+// resharded with from the name and parameters recorded in its manifest.
+// vnodesPerTablet is only used when name is "consistent-hash"; pass the
+// manifest's Manifest.VnodesPerTablet (zero falls back to
+// defaultVnodesPerTablet, matching NewConsistentHashPartitioner).
+func partitionerByName(name string, vnodesPerTablet int) (Partitioner, error) {
+	switch name {
+	case "", "fnv-even":
+		return &FNVEvenPartitioner{}, nil
+	case "consistent-hash":
+		return NewConsistentHashPartitioner(vnodesPerTablet), nil
+	default:
+		return nil, fmt.Errorf("Unknown partitioner: %v", name)
+	}
+}
+
+// FNVEvenPartitioner assigns objects to tablets by hashing the object id
+// with FNV1a and taking the even bits of the hash modulo the tablet count.
+// This is the table's original, pre-Partitioner behavior.
+type FNVEvenPartitioner struct{}
+
+func (p *FNVEvenPartitioner) Name() string {
+	return "fnv-even"
+}
+
+func (p *FNVEvenPartitioner) Assign(objectId interface{}, n int) (uint32, error) {
+	encodedObjectId, err := EncodeObjectId(objectId)
+	if err != nil {
+		return 0, err
+	}
+
+	h := fnv.New64a()
+	h.Reset()
+	h.Write(encodedObjectId)
+	hashcode := h.Sum64()
+
+	return CondenseUint64Even(hashcode) % uint32(n), nil
+}
+
+// defaultVnodesPerTablet is the number of points each tablet claims on the
+// consistent hash ring.
+const defaultVnodesPerTablet = 128
+
+// ConsistentHashPartitioner assigns objects to tablets using a ring of
+// virtual nodes, so that a Reshard only has to move roughly 1/n of the
+// objects instead of rehashing nearly all of them.
+type ConsistentHashPartitioner struct {
+	VnodesPerTablet int
+
+	mu    sync.Mutex
+	rings map[int][]vnode
+}
+
+// NewConsistentHashPartitioner returns a ConsistentHashPartitioner that
+// places vnodesPerTablet points on the ring for each tablet.
+func NewConsistentHashPartitioner(vnodesPerTablet int) *ConsistentHashPartitioner {
+	return &ConsistentHashPartitioner{VnodesPerTablet: vnodesPerTablet}
+}
+
+func (p *ConsistentHashPartitioner) Name() string {
+	return "consistent-hash"
+}
+
+func (p *ConsistentHashPartitioner) Assign(objectId interface{}, n int) (uint32, error) {
+	encodedObjectId, err := EncodeObjectId(objectId)
+	if err != nil {
+		return 0, err
+	}
+
+	h := fnv.New64a()
+	h.Reset()
+	h.Write(encodedObjectId)
+	key := h.Sum64()
+
+	ring := p.ring(n)
+	i := sort.Search(len(ring), func(i int) bool { return ring[i].hash >= key })
+	if i == len(ring) {
+		i = 0
+	}
+
+	return ring[i].tablet, nil
+}
+
+type vnode struct {
+	hash   uint64
+	tablet uint32
+}
+
+// ring returns the sorted hash ring for n tablets, building and caching it
+// on first use so that Assign only has to binary search it rather than
+// rebuild and re-sort it on every call.
+func (p *ConsistentHashPartitioner) ring(n int) []vnode {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if ring, ok := p.rings[n]; ok {
+		return ring
+	}
+
+	vnodes := p.VnodesPerTablet
+	if vnodes <= 0 {
+		vnodes = defaultVnodesPerTablet
+	}
+
+	ring := make([]vnode, 0, n*vnodes)
+	for tablet := 0; tablet < n; tablet++ {
+		for v := 0; v < vnodes; v++ {
+			h := fnv.New64a()
+			h.Reset()
+			fmt.Fprintf(h, "%d-%d", tablet, v)
+			ring = append(ring, vnode{hash: h.Sum64(), tablet: uint32(tablet)})
+		}
+	}
+
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	if p.rings == nil {
+		p.rings = make(map[int][]vnode)
+	}
+	p.rings[n] = ring
+
+	return ring
+}
@@ -0,0 +1,113 @@
+package skyd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFNVEvenPartitionerIsStableForSameInputs(t *testing.T) {
+	p := &FNVEvenPartitioner{}
+
+	a, err := p.Assign("object-1", 8)
+	if err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	b, err := p.Assign("object-1", 8)
+	if err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	if a != b {
+		t.Fatalf("expected the same object id to assign to the same tablet, got %v and %v", a, b)
+	}
+	if a >= 8 {
+		t.Fatalf("assigned tablet %v out of range [0, 8)", a)
+	}
+}
+
+func TestConsistentHashPartitionerCachesRingAcrossAssigns(t *testing.T) {
+	p := NewConsistentHashPartitioner(4)
+
+	if _, err := p.Assign("a", 8); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	first := p.rings[8]
+
+	if _, err := p.Assign("b", 8); err != nil {
+		t.Fatalf("Assign: %v", err)
+	}
+	second := p.rings[8]
+
+	if len(first) != len(second) || &first[0] != &second[0] {
+		t.Fatalf("expected the ring for n=8 to be built once and reused, got distinct slices")
+	}
+}
+
+func TestOpenRestoresConsistentHashPartitionerVnodesPerTablet(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	table.SetPartitioner(NewConsistentHashPartitioner(8))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	before, err := table.GetObjectTabletIndex("object-1")
+	if err != nil {
+		t.Fatalf("GetObjectTabletIndex: %v", err)
+	}
+	table.Close()
+
+	reopened := NewTable(table.Path)
+	if err := reopened.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer reopened.Close()
+
+	chp, ok := reopened.partitioner.(*ConsistentHashPartitioner)
+	if !ok {
+		t.Fatalf("expected a ConsistentHashPartitioner after reopening, got %T", reopened.partitioner)
+	}
+	if chp.VnodesPerTablet != 8 {
+		t.Fatalf("expected VnodesPerTablet 8 to be restored from the manifest, got %v", chp.VnodesPerTablet)
+	}
+
+	after, err := reopened.GetObjectTabletIndex("object-1")
+	if err != nil {
+		t.Fatalf("GetObjectTabletIndex: %v", err)
+	}
+	if before != after {
+		t.Fatalf("expected the same object to assign to the same tablet before and after reopening, got %v and %v", before, after)
+	}
+}
+
+func TestConsistentHashPartitionerMovesFewKeysOnGrowth(t *testing.T) {
+	p := NewConsistentHashPartitioner(128)
+
+	const objects = 2000
+	before := make([]uint32, objects)
+	for i := 0; i < objects; i++ {
+		idx, err := p.Assign(i, 8)
+		if err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		before[i] = idx
+	}
+
+	moved := 0
+	for i := 0; i < objects; i++ {
+		idx, err := p.Assign(i, 9)
+		if err != nil {
+			t.Fatalf("Assign: %v", err)
+		}
+		if idx != before[i] {
+			moved++
+		}
+	}
+
+	// Growing from 8 to 9 tablets should move roughly 1/9th of keys, not
+	// the near-total reshuffle a naive mod-based scheme would cause.
+	if moved > objects/3 {
+		t.Fatalf("expected consistent hashing to move a small fraction of keys, moved %v/%v", moved, objects)
+	}
+}
@@ -0,0 +1,128 @@
+package skyd
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// A Query is run against a single tablet by ParallelQuery. What it contains
+// is up to the caller; Table only knows how to fan it out.
+type Query interface {
+	// Run executes the query against a single tablet and returns that
+	// tablet's contribution to the overall result.
+	Run(ctx context.Context, tablet *Tablet) (interface{}, error)
+}
+
+// A Result is the outcome of a ParallelQuery. Values has one slot per
+// tablet, in tablet order: the value of each tablet that answered
+// successfully, and nil for a tablet that failed or was never queried.
+// Errors maps the index of each tablet that failed to its error.
+type Result struct {
+	Values []interface{}
+	Errors map[int]error
+}
+
+// ForEachTablet calls fn once per tablet, running up to t.ReadConcurrency
+// calls at a time. It stops starting new calls as soon as fn returns an
+// error or ctx is cancelled, and returns the first such error.
+func (t *Table) ForEachTablet(ctx context.Context, fn func(*Tablet) error) error {
+	if !t.IsOpen() {
+		return errTableNotOpen
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, t.readConcurrency())
+	errs := make(chan error, len(t.Tablets))
+	var wg sync.WaitGroup
+
+	for _, tablet := range t.Tablets {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(tablet *Tablet) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if ctx.Err() != nil {
+				return
+			}
+			if err := fn(tablet); err != nil {
+				errs <- err
+				cancel()
+			}
+		}(tablet)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		return err
+	}
+	return ctx.Err()
+}
+
+// ParallelQuery runs q against every tablet, fanning out up to
+// t.ReadConcurrency requests at a time, analogous to Vitess's
+// --topo_read_concurrency knob. Outstanding tablet reads are cancelled as
+// soon as one tablet returns an error or ctx is cancelled. The Result
+// always reflects every tablet that answered before that point, along with
+// a per-tablet error map, so callers can decide whether to retry only the
+// failed shards.
+func (t *Table) ParallelQuery(ctx context.Context, q Query) (Result, error) {
+	if !t.IsOpen() {
+		return Result{}, errTableNotOpen
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := make(chan struct{}, t.readConcurrency())
+	values := make([]interface{}, len(t.Tablets))
+	errs := make(map[int]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i, tablet := range t.Tablets {
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, tablet *Tablet) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			value, err := q.Run(ctx, tablet)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[i] = err
+				cancel()
+				return
+			}
+			values[i] = value
+		}(i, tablet)
+	}
+
+	wg.Wait()
+
+	return Result{Values: values, Errors: errs}, nil
+}
+
+// readConcurrency returns the configured ReadConcurrency, defaulting to
+// runtime.NumCPU() when it hasn't been set.
+func (t *Table) readConcurrency() int {
+	if t.ReadConcurrency > 0 {
+		return t.ReadConcurrency
+	}
+	return runtime.NumCPU()
+}
@@ -0,0 +1,45 @@
+package skyd
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestForEachTabletStopsStartingWorkAfterError(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	// Force multiple tablets regardless of how many CPUs this machine has,
+	// so the fan-out actually has more than one tablet to stop early on.
+	if err := table.growTablets(5); err != nil {
+		t.Fatalf("growTablets: %v", err)
+	}
+
+	table.ReadConcurrency = 1
+
+	var calls int32
+	failAfter := errors.New("boom")
+	err := table.ForEachTablet(context.Background(), func(tablet *Tablet) error {
+		n := atomic.AddInt32(&calls, 1)
+		if n == 1 {
+			return failAfter
+		}
+		return nil
+	})
+	if err != failAfter {
+		t.Fatalf("expected failAfter error, got %v", err)
+	}
+
+	if got := int(atomic.LoadInt32(&calls)); got >= len(table.Tablets) {
+		t.Fatalf("expected fan-out to stop early after an error, but all %v of %v tablets were called", got, len(table.Tablets))
+	}
+}
@@ -0,0 +1,172 @@
+package skyd
+
+import (
+	"errors"
+)
+
+// A VersionVector is a map of node id to the highest event counter seen
+// from that node. Events carry a VersionVector instead of a wall-clock
+// timestamp so that replicas can tell whether one event supersedes another,
+// is superseded by it, or is concurrent with it.
+type VersionVector map[string]uint64
+
+// Dominates reports whether v strictly dominates other: every counter in v
+// is greater than or equal to the matching counter in other, and at least
+// one is strictly greater.
+func (v VersionVector) Dominates(other VersionVector) bool {
+	strictlyGreater := false
+	for node, count := range other {
+		if v[node] < count {
+			return false
+		}
+		if v[node] > count {
+			strictlyGreater = true
+		}
+	}
+	for node, count := range v {
+		if _, ok := other[node]; !ok && count > 0 {
+			strictlyGreater = true
+		}
+	}
+	return strictlyGreater
+}
+
+// Equal reports whether v and other carry the same counters.
+func (v VersionVector) Equal(other VersionVector) bool {
+	if len(v) != len(other) {
+		return false
+	}
+	for node, count := range v {
+		if other[node] != count {
+			return false
+		}
+	}
+	return true
+}
+
+// A Replica identifies a peer that a tablet's append stream is shipped to.
+type Replica struct {
+	NodeID    string
+	Transport Transport
+}
+
+// A Transport ships events to a remote node and fetches events a local
+// replica is missing.
+type Transport interface {
+	Send(tabletIndex int, objectId interface{}, event *Event) error
+	Fetch(tabletIndex int, objectId interface{}) ([]*Event, error)
+}
+
+// Replicas returns the peers that this table's tablets ship their append
+// streams to.
+func (t *Table) Replicas() []*Replica {
+	return t.replicas
+}
+
+// AddReplica registers a peer that should receive a copy of every event
+// appended to this table.
+func (t *Table) AddReplica(replica *Replica) {
+	t.replicas = append(t.replicas, replica)
+}
+
+// NodeID returns the identifier this table uses to stamp the version
+// vectors of events it originates.
+func (t *Table) NodeID() string {
+	return t.nodeID
+}
+
+// SetNodeID sets the identifier this table uses to stamp the version
+// vectors of events it originates. It must be called before AddEvent.
+func (t *Table) SetNodeID(nodeID string) {
+	t.nodeID = nodeID
+}
+
+// nextVectorClock increments this node's own counter and returns a copy of
+// the resulting vector clock to stamp onto the event being appended.
+func (t *Table) nextVectorClock() VersionVector {
+	if t.clock == nil {
+		t.clock = VersionVector{}
+	}
+	t.clock[t.nodeID]++
+
+	clock := make(VersionVector, len(t.clock))
+	for node, count := range t.clock {
+		clock[node] = count
+	}
+	return clock
+}
+
+// replicate ships event to every registered replica for the tablet it was
+// just appended to. A transport error for one replica does not prevent
+// shipping to the others.
+func (t *Table) replicate(tabletIndex int, objectId interface{}, event *Event) {
+	for _, replica := range t.replicas {
+		replica.Transport.Send(tabletIndex, objectId, event)
+	}
+}
+
+// Reconcile pulls events this table is missing from its replicas by
+// comparing version vectors, and merges any conflicting events it finds.
+// An incoming event is accepted if its vector strictly dominates the local
+// one, discarded if it is dominated by the local one, and kept alongside
+// the local event as a sibling if the two are concurrent. Only objects that
+// already have events in a local tablet are checked; objects this table
+// holds no events for are not fetched.
+func (t *Table) Reconcile() error {
+	if !t.IsOpen() {
+		return errors.New("Table is not open")
+	}
+
+	for tabletIndex, tablet := range t.Tablets {
+		err := tablet.ForEachObject(func(objectId interface{}, local []*Event) error {
+			changed := false
+			for _, replica := range t.replicas {
+				remote, err := replica.Transport.Fetch(tabletIndex, objectId)
+				if err != nil {
+					return err
+				}
+				for _, event := range remote {
+					var merged bool
+					local, merged = mergeEvent(local, event)
+					changed = changed || merged
+				}
+			}
+
+			if !changed {
+				return nil
+			}
+			return tablet.SetEvents(objectId, local)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mergeEvent compares remote against every event already held locally for
+// an object and returns the resulting set of events plus whether it
+// differs from local. remote is accepted as a new sibling unless it is
+// equal to or dominated by an existing local event, and any local event
+// that remote dominates is discarded.
+func mergeEvent(local []*Event, remote *Event) ([]*Event, bool) {
+	kept := make([]*Event, 0, len(local)+1)
+
+	for _, event := range local {
+		if event.VectorClock.Equal(remote.VectorClock) || event.VectorClock.Dominates(remote.VectorClock) {
+			// Already have this event, or it's stale relative to one we
+			// already have: keep local as-is and drop remote.
+			return local, false
+		}
+		if remote.VectorClock.Dominates(event.VectorClock) {
+			// remote supersedes this local event; discard the local one.
+			continue
+		}
+		// Concurrent: keep the local event as a sibling.
+		kept = append(kept, event)
+	}
+
+	kept = append(kept, remote)
+	return kept, true
+}
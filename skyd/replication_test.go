@@ -0,0 +1,89 @@
+package skyd
+
+import "testing"
+
+func TestVersionVectorDominatesAndEqual(t *testing.T) {
+	a := VersionVector{"a": 2, "b": 1}
+	b := VersionVector{"a": 1, "b": 1}
+
+	if !a.Dominates(b) {
+		t.Fatalf("expected %v to dominate %v", a, b)
+	}
+	if b.Dominates(a) {
+		t.Fatalf("did not expect %v to dominate %v", b, a)
+	}
+
+	c := VersionVector{"a": 2, "b": 1}
+	if !a.Equal(c) {
+		t.Fatalf("expected %v to equal %v", a, c)
+	}
+
+	concurrent := VersionVector{"a": 1, "b": 2}
+	if a.Dominates(concurrent) || concurrent.Dominates(a) {
+		t.Fatalf("did not expect %v and %v to dominate each other", a, concurrent)
+	}
+}
+
+func TestMergeEventSkipsEqualAndDominated(t *testing.T) {
+	local := &Event{VectorClock: VersionVector{"a": 2}}
+
+	equal := &Event{VectorClock: VersionVector{"a": 2}}
+	merged, changed := mergeEvent([]*Event{local}, equal)
+	if changed || len(merged) != 1 || merged[0] != local {
+		t.Fatalf("expected an equal remote event to be a no-op, got changed=%v merged=%v", changed, merged)
+	}
+
+	older := &Event{VectorClock: VersionVector{"a": 1}}
+	merged, changed = mergeEvent([]*Event{local}, older)
+	if changed || len(merged) != 1 || merged[0] != local {
+		t.Fatalf("expected a dominated remote event to be discarded, got changed=%v merged=%v", changed, merged)
+	}
+}
+
+func TestMergeEventReplacesDominatedLocal(t *testing.T) {
+	local := &Event{VectorClock: VersionVector{"a": 1}}
+	newer := &Event{VectorClock: VersionVector{"a": 2}}
+
+	merged, changed := mergeEvent([]*Event{local}, newer)
+	if !changed || len(merged) != 1 || merged[0] != newer {
+		t.Fatalf("expected the dominated local event to be replaced by remote, got changed=%v merged=%v", changed, merged)
+	}
+}
+
+func TestMergeEventKeepsConcurrentEventsAsSiblings(t *testing.T) {
+	local := &Event{VectorClock: VersionVector{"a": 1}}
+	concurrent := &Event{VectorClock: VersionVector{"b": 1}}
+
+	merged, changed := mergeEvent([]*Event{local}, concurrent)
+	if !changed || len(merged) != 2 {
+		t.Fatalf("expected concurrent events to be kept as siblings, got changed=%v merged=%v", changed, merged)
+	}
+}
+
+func TestAddEventStampsIncrementingVectorClock(t *testing.T) {
+	table := NewTable(t.TempDir() + "/table")
+	table.SetNodeID("node-a")
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	first := &Event{}
+	if err := table.AddEvent("obj", first); err != nil {
+		t.Fatalf("AddEvent: %v", err)
+	}
+	second := &Event{}
+	if err := table.AddEvent("obj", second); err != nil {
+		t.Fatalf("AddEvent: %v", err)
+	}
+
+	if first.VectorClock["node-a"] != 1 {
+		t.Fatalf("expected first event to be stamped with counter 1, got %v", first.VectorClock)
+	}
+	if second.VectorClock["node-a"] != 2 {
+		t.Fatalf("expected second event to be stamped with counter 2, got %v", second.VectorClock)
+	}
+}
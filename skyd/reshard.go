@@ -0,0 +1,178 @@
+package skyd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// reshardJournalFileName is the name of the file, stored in the table root,
+// used to make Reshard resumable across a crash.
+const reshardJournalFileName = "reshard.journal"
+
+// A reshardJournal records the tablet count a Reshard is moving towards.
+// Progress itself doesn't need to be tracked separately: resumeReshard only
+// adds an object's events to its destination tablet if they aren't already
+// there, and only removes an object from its source tablet once its events
+// are confirmed at the destination, so re-running it against whatever
+// objects are still sitting in the wrong tablet is always safe.
+type reshardJournal struct {
+	TargetCount int `json:"targetCount"`
+}
+
+// Returns the path of the table's reshard journal.
+func (t *Table) reshardJournalPath() string {
+	return filepath.Join(t.Path, reshardJournalFileName)
+}
+
+// Loads the reshard journal from disk, if one exists.
+func (t *Table) loadReshardJournal() (*reshardJournal, error) {
+	f, err := os.Open(t.reshardJournalPath())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	j := &reshardJournal{}
+	if err := json.NewDecoder(f).Decode(j); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Writes the reshard journal to disk.
+func (t *Table) saveReshardJournal(j *reshardJournal) error {
+	f, err := os.Create(t.reshardJournalPath())
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return json.NewEncoder(f).Encode(j)
+}
+
+// Removes the reshard journal once a reshard has completed.
+func (t *Table) clearReshardJournal() error {
+	err := os.Remove(t.reshardJournalPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Reshard changes the number of tablets a table is split across, moving
+// every object to the tablet its id hashes to under the new tablet count.
+// Both growing and shrinking are supported: growing creates new tablet
+// directories before objects are moved into them, and shrinking removes
+// the source tablets once every object they held has been drained out of
+// them. Progress is recorded in a journal in the table root so that a
+// crash mid-reshard can be resumed the next time Open is called.
+func (t *Table) Reshard(n int) error {
+	if !t.IsOpen() {
+		return errors.New("Table is not open")
+	}
+	if n <= 0 {
+		return fmt.Errorf("Invalid tablet count: %v", n)
+	}
+
+	journal := &reshardJournal{TargetCount: n}
+	if err := t.saveReshardJournal(journal); err != nil {
+		return err
+	}
+
+	if err := t.growTablets(n); err != nil {
+		return err
+	}
+
+	if err := t.resumeReshard(journal); err != nil {
+		return err
+	}
+
+	if err := t.clearReshardJournal(); err != nil {
+		return err
+	}
+
+	return t.saveManifest(t.manifestFor(n))
+}
+
+// Creates any additional tablet directories needed to reach n tablets.
+func (t *Table) growTablets(n int) error {
+	for i := len(t.Tablets); i < n; i++ {
+		path := fmt.Sprintf("%v/%v", t.Path, i)
+		if err := os.MkdirAll(path, 0700); err != nil {
+			return err
+		}
+
+		tablet := NewTablet(path)
+		if err := tablet.Open(); err != nil {
+			return err
+		}
+		t.Tablets = append(t.Tablets, tablet)
+	}
+	return nil
+}
+
+// resumeReshard drains every tablet, recomputing each object's destination
+// tablet under the journal's target tablet count and moving its events
+// across. Once every tablet beyond the target count is fully drained, its
+// directory is removed.
+func (t *Table) resumeReshard(journal *reshardJournal) error {
+	srcCount := len(t.Tablets)
+	for i := 0; i < srcCount; i++ {
+		src := t.Tablets[i]
+		err := src.ForEachObject(func(objectId interface{}, events []*Event) error {
+			destIndex, err := t.objectTabletIndex(objectId, journal.TargetCount)
+			if err != nil {
+				return err
+			}
+			if int(destIndex) == i {
+				return nil
+			}
+
+			// A crash may have already written these events to the
+			// destination tablet on a previous attempt; skip re-adding
+			// them so a retry can't duplicate events, and just finish
+			// removing the object from the source. The events are written
+			// in a single SetEvents call rather than one at a time, so an
+			// interrupted attempt never leaves only some of them behind.
+			dest := t.Tablets[destIndex]
+			existing, err := dest.GetEvents(objectId)
+			if err != nil {
+				return err
+			}
+			if len(existing) == 0 {
+				if err := dest.SetEvents(objectId, events); err != nil {
+					return err
+				}
+			}
+
+			return src.DeleteObject(objectId)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.shrinkTablets(journal.TargetCount)
+}
+
+// shrinkTablets removes the tablets at index n and beyond, which by this
+// point have had every object they held moved elsewhere by resumeReshard.
+func (t *Table) shrinkTablets(n int) error {
+	if n >= len(t.Tablets) {
+		return nil
+	}
+
+	for i := n; i < len(t.Tablets); i++ {
+		tablet := t.Tablets[i]
+		tablet.Close()
+		if err := os.RemoveAll(tablet.Path); err != nil {
+			return err
+		}
+	}
+
+	t.Tablets = t.Tablets[:n]
+	return nil
+}
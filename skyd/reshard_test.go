@@ -0,0 +1,201 @@
+package skyd
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestReshardGrowMovesObjectsAndRemovesSourceDuplicates(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	table.SetPartitioner(&FNVEvenPartitioner{})
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	originalCount := len(table.Tablets)
+
+	for i := 0; i < 50; i++ {
+		if err := table.AddEvent(i, &Event{}); err != nil {
+			t.Fatalf("AddEvent(%v): %v", i, err)
+		}
+	}
+
+	if err := table.Reshard(originalCount * 4); err != nil {
+		t.Fatalf("Reshard: %v", err)
+	}
+
+	if len(table.Tablets) != originalCount*4 {
+		t.Fatalf("expected %v tablets after reshard, got %v", originalCount*4, len(table.Tablets))
+	}
+
+	for i := 0; i < 50; i++ {
+		events, err := table.GetEvents(i)
+		if err != nil {
+			t.Fatalf("GetEvents(%v): %v", i, err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("object %v: expected exactly 1 event after reshard, got %v (duplicate or lost event)", i, len(events))
+		}
+	}
+
+	stored := 0
+	for _, tablet := range table.Tablets {
+		err := tablet.ForEachObject(func(objectId interface{}, events []*Event) error {
+			stored++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ForEachObject: %v", err)
+		}
+	}
+	if stored != 50 {
+		t.Fatalf("expected 50 stored objects across all tablets after reshard, got %v (objects left behind in their source tablet)", stored)
+	}
+}
+
+func TestResumeReshardIsIdempotentAfterPartialMove(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := table.AddEvent(i, &Event{}); err != nil {
+			t.Fatalf("AddEvent(%v): %v", i, err)
+		}
+	}
+
+	originalCount := len(table.Tablets)
+	target := originalCount * 2
+	journal := &reshardJournal{TargetCount: target}
+	if err := table.growTablets(target); err != nil {
+		t.Fatalf("growTablets: %v", err)
+	}
+
+	// Simulate a crash mid-reshard: copy each object's events to its
+	// destination tablet while leaving it in its source tablet, as an
+	// attempt interrupted between those two steps would.
+	for i := 0; i < 20; i++ {
+		from, err := table.objectTabletIndex(i, originalCount)
+		if err != nil {
+			t.Fatalf("objectTabletIndex(%v): %v", i, err)
+		}
+		to, err := table.objectTabletIndex(i, target)
+		if err != nil {
+			t.Fatalf("objectTabletIndex(%v): %v", i, err)
+		}
+		if from == to {
+			continue
+		}
+		events, err := table.Tablets[from].GetEvents(i)
+		if err != nil {
+			t.Fatalf("GetEvents(%v): %v", i, err)
+		}
+		if err := table.Tablets[to].SetEvents(i, events); err != nil {
+			t.Fatalf("SetEvents(%v): %v", i, err)
+		}
+	}
+
+	// Then run the move twice, as Open would on recovery, and make sure no
+	// object ends up duplicated.
+	if err := table.resumeReshard(journal); err != nil {
+		t.Fatalf("resumeReshard (1st pass): %v", err)
+	}
+	if err := table.resumeReshard(journal); err != nil {
+		t.Fatalf("resumeReshard (2nd pass): %v", err)
+	}
+
+	for i := 0; i < 20; i++ {
+		events, err := table.GetEvents(i)
+		if err != nil {
+			t.Fatalf("GetEvents(%v): %v", i, err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("object %v: expected exactly 1 event after repeated resume, got %v", i, len(events))
+		}
+	}
+}
+
+func TestOpenReloadsAllTabletsPastTenAfterReshard(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	for i := 0; i < 100; i++ {
+		if err := table.AddEvent(i, &Event{}); err != nil {
+			t.Fatalf("AddEvent(%v): %v", i, err)
+		}
+	}
+
+	if err := table.Reshard(15); err != nil {
+		t.Fatalf("Reshard: %v", err)
+	}
+	table.Close()
+
+	reopened := NewTable(table.Path)
+	if err := reopened.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer reopened.Close()
+
+	if len(reopened.Tablets) != 15 {
+		t.Fatalf("expected 15 tablets after reopening a resharded table, got %v", len(reopened.Tablets))
+	}
+
+	for i := 0; i < 100; i++ {
+		events, err := reopened.GetEvents(i)
+		if err != nil {
+			t.Fatalf("GetEvents(%v): %v", i, err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("object %v: expected exactly 1 event after reopening, got %v", i, len(events))
+		}
+	}
+}
+
+func TestReshardShrinkRemovesDrainedTablets(t *testing.T) {
+	table := NewTable(filepath.Join(t.TempDir(), "table"))
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	for i := 0; i < 20; i++ {
+		if err := table.AddEvent(i, &Event{}); err != nil {
+			t.Fatalf("AddEvent(%v): %v", i, err)
+		}
+	}
+
+	if err := table.Reshard(1); err != nil {
+		t.Fatalf("Reshard: %v", err)
+	}
+
+	if len(table.Tablets) != 1 {
+		t.Fatalf("expected 1 tablet after shrink, got %v", len(table.Tablets))
+	}
+
+	for i := 0; i < 20; i++ {
+		events, err := table.GetEvents(i)
+		if err != nil {
+			t.Fatalf("GetEvents(%v): %v", i, err)
+		}
+		if len(events) != 1 {
+			t.Fatalf("object %v: expected exactly 1 event after shrink, got %v", i, len(events))
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package skyd
+
+import (
+	"fmt"
+	"time"
+)
+
+// defaultHealthCheckInterval is how often a table pings its tablets when no
+// interval has been configured.
+const defaultHealthCheckInterval = 5 * time.Second
+
+// defaultMaxHealthFailures is how many consecutive failed health checks a
+// tablet tolerates before it is automatically demoted.
+const defaultMaxHealthFailures = 3
+
+// tabletState tracks the routing-relevant state of a single tablet: the
+// role it was promoted to, whether it is currently healthy enough to serve
+// requests, and how recently a health check last confirmed that.
+type tabletState struct {
+	role             TabletType
+	online           bool
+	consecutiveFails int
+	lastHealthyAt    time.Time
+
+	// autoOffline records whether the health-check loop is the one that
+	// took this tablet offline. Only a tablet in that state is eligible
+	// to be automatically re-promoted on its next successful ping; one
+	// an operator took offline via DemoteTablet stays offline until the
+	// operator calls PromoteTablet, e.g. for a RDONLY pulled out of
+	// rotation for batch analytics.
+	autoOffline bool
+}
+
+// PromoteTablet assigns role to the tablet at index and marks it online.
+func (t *Table) PromoteTablet(index int, role TabletType) error {
+	t.statesMu.Lock()
+	defer t.statesMu.Unlock()
+
+	if index < 0 || index >= len(t.Tablets) {
+		return fmt.Errorf("Tablet index out of range: %v", index)
+	}
+
+	t.ensureTabletStatesLocked()
+	t.tabletStates[index].role = role
+	t.tabletStates[index].online = true
+	t.tabletStates[index].consecutiveFails = 0
+	t.tabletStates[index].lastHealthyAt = time.Now()
+	t.tabletStates[index].autoOffline = false
+
+	return nil
+}
+
+// DemoteTablet takes the tablet at index offline so it stops serving
+// requests, without changing its declared role. Because this is an
+// operator-initiated demotion, the health-check loop will not
+// automatically re-promote the tablet; call PromoteTablet to bring it
+// back.
+func (t *Table) DemoteTablet(index int) error {
+	t.statesMu.Lock()
+	defer t.statesMu.Unlock()
+
+	if index < 0 || index >= len(t.Tablets) {
+		return fmt.Errorf("Tablet index out of range: %v", index)
+	}
+
+	t.ensureTabletStatesLocked()
+	t.tabletStates[index].online = false
+	t.tabletStates[index].autoOffline = false
+
+	return nil
+}
+
+// TabletRole returns the role the tablet at index was promoted to. Tablets
+// default to MASTER until PromoteTablet is called.
+func (t *Table) TabletRole(index int) TabletType {
+	t.statesMu.Lock()
+	defer t.statesMu.Unlock()
+
+	t.ensureTabletStatesLocked()
+	return t.tabletStates[index].role
+}
+
+// ensureTabletStatesLocked lazily grows tabletStates to cover every tablet,
+// defaulting new entries to an online MASTER so routing behaves exactly as
+// it did before roles existed. Callers must hold statesMu.
+func (t *Table) ensureTabletStatesLocked() {
+	for len(t.tabletStates) < len(t.Tablets) {
+		t.tabletStates = append(t.tabletStates, &tabletState{role: MASTER, online: true, lastHealthyAt: time.Now()})
+	}
+}
+
+// routeForWrite returns an error if the tablet at tabletIndex is not
+// currently an online MASTER.
+func (t *Table) routeForWrite(tabletIndex uint32) error {
+	t.statesMu.Lock()
+	defer t.statesMu.Unlock()
+
+	t.ensureTabletStatesLocked()
+	state := t.tabletStates[tabletIndex]
+	if !state.online {
+		return fmt.Errorf("Tablet %v is offline", tabletIndex)
+	}
+	if state.role != MASTER {
+		return fmt.Errorf("Tablet %v is not a MASTER (role: %v)", tabletIndex, state.role)
+	}
+	return nil
+}
+
+// routeForRead returns an error if the tablet at tabletIndex cannot
+// currently serve reads. MASTER tablets always can, as long as they are
+// online. REPLICA and RDONLY tablets must additionally have passed a
+// health check within t.ReadStaleness, so that a replica that has stopped
+// receiving updates stops being read from even before enough failed health
+// checks accumulate to demote it outright.
+func (t *Table) routeForRead(tabletIndex uint32) error {
+	t.statesMu.Lock()
+	defer t.statesMu.Unlock()
+
+	t.ensureTabletStatesLocked()
+	state := t.tabletStates[tabletIndex]
+	if !state.online {
+		return fmt.Errorf("Tablet %v is offline", tabletIndex)
+	}
+
+	if state.role != MASTER && t.ReadStaleness > 0 {
+		if age := time.Since(state.lastHealthyAt); age > t.ReadStaleness {
+			return fmt.Errorf("Tablet %v is too stale to serve reads: last healthy %v ago", tabletIndex, age)
+		}
+	}
+
+	return nil
+}
+
+// StartHealthCheck begins periodically pinging every tablet so that a
+// tablet which stops answering is automatically demoted (taken offline)
+// and re-promoted once it recovers. Use interval <= 0 or maxFailures <= 0
+// to accept the defaults.
+func (t *Table) StartHealthCheck(interval time.Duration, maxFailures int) {
+	t.initHealthCheck(interval, maxFailures)
+}
+
+// StopHealthCheck stops the periodic health check started by
+// StartHealthCheck.
+func (t *Table) StopHealthCheck() {
+	t.stopHealthCheck()
+}
+
+// initHealthCheck starts a goroutine that pings every tablet on the given
+// interval, demoting (taking offline) any tablet that fails maxFailures
+// consecutive pings and re-promoting it as soon as it answers again.
+func (t *Table) initHealthCheck(interval time.Duration, maxFailures int) {
+	if interval <= 0 {
+		interval = defaultHealthCheckInterval
+	}
+	if maxFailures <= 0 {
+		maxFailures = defaultMaxHealthFailures
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	t.healthCheckDone = done
+	t.healthCheckStopped = stopped
+	ticker := time.NewTicker(interval)
+
+	go func() {
+		defer ticker.Stop()
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				t.runHealthChecks(maxFailures)
+			case <-done:
+				return
+			}
+		}
+	}()
+}
+
+// stopHealthCheck stops the health-check loop started by initHealthCheck
+// and waits for its goroutine to exit, so a caller that goes on to clear
+// tabletStates (as Close does) can't race an in-flight runHealthChecks.
+func (t *Table) stopHealthCheck() {
+	if t.healthCheckDone != nil {
+		close(t.healthCheckDone)
+		t.healthCheckDone = nil
+		<-t.healthCheckStopped
+		t.healthCheckStopped = nil
+	}
+}
+
+// runHealthChecks pings every tablet once, demoting tablets that have
+// failed maxFailures times in a row and re-promoting tablets that answer
+// after having been taken offline by this same loop. A tablet an operator
+// took offline via DemoteTablet is left alone: only a tablet this loop
+// demoted itself is eligible for automatic re-promotion.
+func (t *Table) runHealthChecks(maxFailures int) {
+	t.statesMu.Lock()
+	defer t.statesMu.Unlock()
+
+	t.ensureTabletStatesLocked()
+
+	for i, tablet := range t.Tablets {
+		state := t.tabletStates[i]
+		if err := tablet.Ping(); err != nil {
+			state.consecutiveFails++
+			if state.consecutiveFails >= maxFailures && state.online {
+				state.online = false
+				state.autoOffline = true
+			}
+			continue
+		}
+
+		state.consecutiveFails = 0
+		if !state.online && !state.autoOffline {
+			continue
+		}
+		state.online = true
+		state.autoOffline = false
+		state.lastHealthyAt = time.Now()
+	}
+}
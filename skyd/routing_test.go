@@ -0,0 +1,162 @@
+package skyd
+
+import (
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRoutingRejectsWritesToNonMasterOrOfflineTablets(t *testing.T) {
+	table := NewTable(t.TempDir() + "/table")
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	if err := table.PromoteTablet(0, REPLICA); err != nil {
+		t.Fatalf("PromoteTablet: %v", err)
+	}
+	if err := table.routeForWrite(0); err == nil {
+		t.Fatalf("expected a REPLICA tablet to reject writes")
+	}
+
+	if err := table.PromoteTablet(0, MASTER); err != nil {
+		t.Fatalf("PromoteTablet: %v", err)
+	}
+	if err := table.DemoteTablet(0); err != nil {
+		t.Fatalf("DemoteTablet: %v", err)
+	}
+	if err := table.routeForWrite(0); err == nil {
+		t.Fatalf("expected an offline tablet to reject writes")
+	}
+}
+
+func TestRoutingEnforcesReadStaleness(t *testing.T) {
+	table := NewTable(t.TempDir() + "/table")
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	table.ReadStaleness = time.Nanosecond
+	if err := table.PromoteTablet(0, REPLICA); err != nil {
+		t.Fatalf("PromoteTablet: %v", err)
+	}
+	time.Sleep(time.Millisecond)
+
+	if err := table.routeForRead(0); err == nil {
+		t.Fatalf("expected a stale REPLICA to reject reads")
+	}
+}
+
+func TestHealthCheckDoesNotRepromoteAnOperatorDemotedTablet(t *testing.T) {
+	table := NewTable(t.TempDir() + "/table")
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	if err := table.DemoteTablet(0); err != nil {
+		t.Fatalf("DemoteTablet: %v", err)
+	}
+
+	// The tablet's directory is untouched, so the ping below succeeds; a
+	// health check seeing a healthy ping must still leave an
+	// operator-demoted tablet offline.
+	table.runHealthChecks(3)
+
+	if err := table.routeForRead(0); err == nil {
+		t.Fatalf("expected an operator-demoted tablet to stay offline after a healthy ping")
+	}
+}
+
+func TestHealthCheckRepromotesATabletItDemotedItself(t *testing.T) {
+	table := NewTable(t.TempDir() + "/table")
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	path := table.Tablets[0].Path
+	asidePath := path + ".aside"
+	if err := os.Rename(path, asidePath); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+
+	const maxFailures = 3
+	for i := 0; i < maxFailures; i++ {
+		table.runHealthChecks(maxFailures)
+	}
+	if err := table.routeForWrite(0); err == nil {
+		t.Fatalf("expected a tablet failing pings to be demoted")
+	}
+
+	if err := os.Rename(asidePath, path); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	table.runHealthChecks(maxFailures)
+
+	if err := table.routeForWrite(0); err != nil {
+		t.Fatalf("expected a recovered, self-demoted tablet to be re-promoted: %v", err)
+	}
+}
+
+// TestCloseDoesNotRaceAnInFlightHealthCheck exercises Close while the
+// health-check loop is actively running, the pattern go test -race is
+// meant to catch against the nil-out of tabletStates in Close.
+func TestCloseDoesNotRaceAnInFlightHealthCheck(t *testing.T) {
+	table := NewTable(t.TempDir() + "/table")
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	table.StartHealthCheck(time.Millisecond, 3)
+	time.Sleep(5 * time.Millisecond)
+	table.Close()
+}
+
+// TestRoutingStateAccessIsRaceFree exercises tabletState concurrently from
+// the health-check goroutine and from caller goroutines doing routing
+// checks, the pattern go test -race is meant to catch.
+func TestRoutingStateAccessIsRaceFree(t *testing.T) {
+	table := NewTable(t.TempDir() + "/table")
+	if err := table.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := table.Open(); err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer table.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			table.runHealthChecks(3)
+		}()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			table.routeForWrite(0)
+			table.routeForRead(0)
+		}()
+	}
+	wg.Wait()
+}
@@ -3,19 +3,44 @@ package skyd
 import (
 	"errors"
 	"fmt"
-	"hash/fnv"
-	"io/ioutil"
 	"os"
 	"path/filepath"
-	"regexp"
 	"runtime"
+	"sync"
+	"time"
 )
 
+// errTableNotOpen is returned whenever an operation that requires an open
+// table is attempted before Open or after Close.
+var errTableNotOpen = errors.New("Table is not open")
+
 // A Table is a collection of tablets.
 type Table struct {
 	Path    string
 	Name    string
 	Tablets []*Tablet
+
+	// ReadConcurrency bounds how many tablets ForEachTablet and
+	// ParallelQuery will read from at once. It defaults to
+	// runtime.NumCPU() when left at zero.
+	ReadConcurrency int
+
+	// ReadStaleness bounds how long a REPLICA or RDONLY tablet can go
+	// without a successful health check and still serve reads. Reads
+	// against a tablet that has been unhealthy for longer than this are
+	// rejected even if it hasn't yet failed enough checks to be demoted.
+	// Zero (the default) disables the staleness check.
+	ReadStaleness time.Duration
+
+	nodeID      string
+	clock       VersionVector
+	replicas    []*Replica
+	partitioner Partitioner
+
+	statesMu           sync.Mutex
+	tabletStates       []*tabletState
+	healthCheckDone    chan struct{}
+	healthCheckStopped chan struct{}
 }
 
 // NewTable returns a new Table that is stored at a given path.
@@ -26,11 +51,21 @@ func NewTable(path string) *Table {
 	}
 
 	return &Table{
-		Path: path,
-		Name: filepath.Base(path),
+		Path:        path,
+		Name:        filepath.Base(path),
+		partitioner: &FNVEvenPartitioner{},
 	}
 }
 
+// SetPartitioner sets the scheme used to assign objects to tablets. Create
+// records it in the table manifest, and Open replaces it with the one
+// recorded there, so setting it between Create and Open has no effect.
+// Setting it on an open table changes which tablet objects are looked up
+// in without moving the objects already stored.
+func (t *Table) SetPartitioner(partitioner Partitioner) {
+	t.partitioner = partitioner
+}
+
 // Creates a table directory structure.
 func (t *Table) Create() error {
 	if t.Exists() {
@@ -44,7 +79,8 @@ func (t *Table) Create() error {
 	}
 
 	// Create a subdirectory for each tablet.
-	for i := 0; i < runtime.NumCPU(); i++ {
+	tabletCount := runtime.NumCPU()
+	for i := 0; i < tabletCount; i++ {
 		err = os.Mkdir(fmt.Sprintf("%v/%v", t.Path, i), 0700)
 		if err != nil {
 			os.RemoveAll(t.Path)
@@ -52,6 +88,22 @@ func (t *Table) Create() error {
 		}
 	}
 
+	// Record the tablet count, hash scheme and partitioner so Open and
+	// Reshard can agree on the table's layout later.
+	err = t.saveManifest(t.manifestFor(tabletCount))
+	if err != nil {
+		os.RemoveAll(t.Path)
+		return err
+	}
+
+	// Stamp the table with the current on-disk format version so Open can
+	// detect and migrate older tables later.
+	err = t.writeFormatVersion(currentFormatVersion)
+	if err != nil {
+		os.RemoveAll(t.Path)
+		return err
+	}
+
 	return nil
 }
 
@@ -61,17 +113,47 @@ func (t *Table) Open() error {
 		return errors.New("Table does not exist")
 	}
 
-	// Create tablets from child directories with numeric names.
-	infos, err := ioutil.ReadDir(t.Path)
+	// Detect an older on-disk format and migrate in place before tablets
+	// are opened against it.
+	if err := t.migrate(); err != nil {
+		return err
+	}
+
+	// Restore the partitioner and tablet count the table was created or
+	// resharded with. Tablets are loaded by manifest.TabletCount rather
+	// than by globbing the table directory for numeric names, since a
+	// glob anchored on a single digit silently drops every tablet past
+	// index 9 once a reshard has grown the table beyond ten tablets.
+	manifest, err := t.loadManifest()
+	if err != nil {
+		return err
+	}
+	partitioner, err := partitionerByName(manifest.Partitioner, manifest.VnodesPerTablet)
 	if err != nil {
 		return err
 	}
-	for _, info := range infos {
-		match, _ := regexp.MatchString("^\\d$", info.Name())
-		if info.IsDir() && match {
-			tablet := NewTablet(fmt.Sprintf("%s/%s", t.Path, info.Name()))
-			t.Tablets = append(t.Tablets, tablet)
-			tablet.Open()
+	t.partitioner = partitioner
+
+	for i := 0; i < manifest.TabletCount; i++ {
+		tablet := NewTablet(fmt.Sprintf("%s/%d", t.Path, i))
+		t.Tablets = append(t.Tablets, tablet)
+		tablet.Open()
+	}
+
+	// If a reshard was interrupted by a crash, pick it back up before the
+	// table is handed back to the caller.
+	if journal, err := t.loadReshardJournal(); err == nil {
+		if err := t.growTablets(journal.TargetCount); err != nil {
+			return err
+		}
+		if err := t.resumeReshard(journal); err != nil {
+			return err
+		}
+		if err := t.clearReshardJournal(); err != nil {
+			return err
+		}
+		if err := t.saveManifest(t.manifestFor(journal.TargetCount)); err != nil {
+			return err
 		}
 	}
 
@@ -80,10 +162,19 @@ func (t *Table) Open() error {
 
 // Closes the table and all the tablets.
 func (t *Table) Close() {
+	// stopHealthCheck joins the health-check goroutine before returning,
+	// so by the time statesMu is taken below no runHealthChecks call is
+	// still in flight to race the nil-out of tabletStates.
+	t.stopHealthCheck()
+
 	for _, tablet := range t.Tablets {
 		tablet.Close()
 	}
 	t.Tablets = nil
+
+	t.statesMu.Lock()
+	t.tabletStates = nil
+	t.statesMu.Unlock()
 }
 
 // Checks if the table is currently open.
@@ -111,6 +202,15 @@ func (t *Table) AddEvent(objectId interface{}, event *Event) error {
 		return err
 	}
 
+	// Writes are only routed to a tablet that is currently an online MASTER.
+	if err := t.routeForWrite(tabletIndex); err != nil {
+		return err
+	}
+
+	// Stamp the event with this node's next vector clock so replicas can
+	// compare causal history instead of relying on wall-clock time.
+	event.VectorClock = t.nextVectorClock()
+
 	// Add event to the appropriate tablet.
 	tablet := t.Tablets[tabletIndex]
 	err = tablet.AddEvent(objectId, event)
@@ -118,6 +218,10 @@ func (t *Table) AddEvent(objectId interface{}, event *Event) error {
 		return err
 	}
 
+	// Ship the event to every replica so the cluster can tolerate the loss
+	// of this node.
+	t.replicate(int(tabletIndex), objectId, event)
+
 	return nil
 }
 
@@ -133,6 +237,12 @@ func (t *Table) GetEvents(objectId interface{}) ([]*Event, error) {
 		return nil, err
 	}
 
+	// Reads can be served by a MASTER, REPLICA or RDONLY tablet as long as
+	// it is online.
+	if err := t.routeForRead(tabletIndex); err != nil {
+		return nil, err
+	}
+
 	// Add event to the appropriate tablet.
 	tablet := t.Tablets[tabletIndex]
 	events, err := tablet.GetEvents(objectId)
@@ -149,18 +259,13 @@ func (t *Table) GetObjectTabletIndex(objectId interface{}) (uint32, error) {
 		return 0, errors.New("Table is not open")
 	}
 
-	// Encode object identifier.
-	encodedObjectId, err := EncodeObjectId(objectId)
-	if err != nil {
-		return 0, err
-	}
-
-	// Calculate the even bits of the FNV1a hash.
-	h := fnv.New64a()
-	h.Reset()
-	h.Write(encodedObjectId)
-	hashcode := h.Sum64()
-	index := CondenseUint64Even(hashcode) % uint32(len(t.Tablets))
+	return t.objectTabletIndex(objectId, len(t.Tablets))
+}
 
-	return index, nil
+// Calculates a tablet index for an object identifier under a given tablet
+// count. This is factored out from GetObjectTabletIndex so that Reshard can
+// compute an object's destination tablet under a tablet count that differs
+// from the table's current one.
+func (t *Table) objectTabletIndex(objectId interface{}, tabletCount int) (uint32, error) {
+	return t.partitioner.Assign(objectId, tabletCount)
 }
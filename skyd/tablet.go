@@ -0,0 +1,162 @@
+package skyd
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// A Tablet stores the events for a subset of a table's objects, one file
+// per object, inside its own directory.
+type Tablet struct {
+	Path string
+	open bool
+}
+
+// NewTablet returns a new Tablet rooted at path. The directory at path is
+// expected to already exist; callers that need to create a new tablet from
+// scratch should make the directory before calling Open.
+func NewTablet(path string) *Tablet {
+	return &Tablet{Path: path}
+}
+
+// Opens the tablet.
+func (t *Tablet) Open() error {
+	if _, err := os.Stat(t.Path); err != nil {
+		return err
+	}
+	t.open = true
+	return nil
+}
+
+// Closes the tablet.
+func (t *Tablet) Close() {
+	t.open = false
+}
+
+// Ping checks that the tablet's backing directory is still reachable, used
+// by the table's health check to detect a tablet that has gone offline
+// (e.g. an unmounted disk).
+func (t *Tablet) Ping() error {
+	_, err := os.Stat(t.Path)
+	return err
+}
+
+// objectPath returns the path of the file an object's events are stored in.
+func (t *Tablet) objectPath(objectId interface{}) (string, error) {
+	encoded, err := EncodeObjectId(objectId)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(t.Path, fmt.Sprintf("%x.json", encoded)), nil
+}
+
+// Adds an event for a given object to the tablet.
+func (t *Tablet) AddEvent(objectId interface{}, event *Event) error {
+	events, err := t.GetEvents(objectId)
+	if err != nil {
+		return err
+	}
+	return t.SetEvents(objectId, append(events, event))
+}
+
+// SetEvents overwrites the events stored for an object, used by Reconcile
+// once it has merged in events pulled from a replica. Setting an empty
+// list removes the object entirely.
+func (t *Tablet) SetEvents(objectId interface{}, events []*Event) error {
+	if len(events) == 0 {
+		return t.DeleteObject(objectId)
+	}
+
+	path, err := t.objectPath(objectId)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+// Retrieves a list of events for a given object.
+func (t *Tablet) GetEvents(objectId interface{}) ([]*Event, error) {
+	path, err := t.objectPath(objectId)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*Event
+	if err := json.Unmarshal(data, &events); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// DeleteObject removes every event stored for an object.
+func (t *Tablet) DeleteObject(objectId interface{}) error {
+	path, err := t.objectPath(objectId)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ForEachObject calls fn once for every object currently stored in the
+// tablet, along with its events.
+func (t *Tablet) ForEachObject(fn func(objectId interface{}, events []*Event) error) error {
+	infos, err := ioutil.ReadDir(t.Path)
+	if err != nil {
+		return err
+	}
+
+	for _, info := range infos {
+		if info.IsDir() || filepath.Ext(info.Name()) != ".json" {
+			continue
+		}
+
+		// Decode the filename back into the raw encoded id bytes, rather
+		// than handing back the hex string, so that re-encoding it via
+		// EncodeObjectId's []byte passthrough yields the same bytes the
+		// object was originally stored under.
+		encoded, err := hex.DecodeString(strings.TrimSuffix(info.Name(), ".json"))
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(filepath.Join(t.Path, info.Name()))
+		if err != nil {
+			return err
+		}
+
+		var events []*Event
+		if err := json.Unmarshal(data, &events); err != nil {
+			return err
+		}
+
+		if err := fn(encoded, events); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package skyd
+
+// A TabletType declares the role a tablet plays within a table, borrowed
+// from Vitess's MASTER/REPLICA/RDONLY split. Table uses it to route writes
+// to the tablet that owns them and reads to whichever tablets are allowed
+// to serve them.
+type TabletType int
+
+const (
+	// MASTER tablets accept both reads and writes.
+	MASTER TabletType = iota
+
+	// REPLICA tablets serve reads within the table's staleness bound.
+	REPLICA
+
+	// RDONLY tablets serve reads but can be taken offline for batch
+	// analytics without affecting live traffic.
+	RDONLY
+)
+
+func (typ TabletType) String() string {
+	switch typ {
+	case MASTER:
+		return "MASTER"
+	case REPLICA:
+		return "REPLICA"
+	case RDONLY:
+		return "RDONLY"
+	default:
+		return "UNKNOWN"
+	}
+}